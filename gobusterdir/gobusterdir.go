@@ -5,13 +5,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
+	"unicode"
 
 	"github.com/OJ/gobuster/v3/libgobuster"
-	"github.com/anaskhan96/soup" // check this lib is safe/well kept
 	"github.com/google/uuid"
+	"golang.org/x/net/html"
 )
 
 // ErrWildcard is returned if a wildcard response is found
@@ -30,18 +37,61 @@ type GobusterDir struct {
 	options    *OptionsDir
 	globalopts *libgobuster.Options
 	http       *libgobuster.HTTPClient
+
+	// hasBaseline/baselineX are populated in PreRun from the wildcard probe and
+	// used to auto-filter soft-404 responses that fall within
+	// --auto-filter-threshold of that baseline.
+	hasBaseline   bool
+	baselineSize  int64
+	baselineWords int
+	baselineLines int
+
+	// scrapedWords/scrapedByHost accumulate across the whole scan (every
+	// worker feeds the same maps) so the --wordlist-scrape output is
+	// deduplicated globally rather than per page, and is flushed once in
+	// Finish rather than once per response.
+	scrapeMu       sync.Mutex
+	scrapedWords   map[string]struct{}
+	scrapedByHost  map[string]map[string]struct{}
+	robotsMu       sync.Mutex
+	robotsDisallow map[string][]string
 }
 
 // GetRequest issues a GET request to the target and returns
-// the status code, length and an error
-func (d *GobusterDir) get(url string, grabwords bool) (*int, *int64, *[]byte, error) {
+// the status code, length, response headers, body (only when grabwords is
+// set) and an error
+func (d *GobusterDir) get(url string, grabwords bool) (*int, *int64, http.Header, *[]byte, error) {
 	if grabwords {
-		statuscode, body, err := d.http.GetWithBody(url, "", d.options.Cookies)
-		return statuscode, nil, body, err
+		statuscode, header, body, err := d.http.GetWithBody(url, "", d.options.Cookies)
+		var length *int64
+		if body != nil {
+			l := int64(len(*body))
+			length = &l
+		}
+		return statuscode, length, header, body, err
 	}
 
-	statuscode, length, err := d.http.Get(url, "", d.options.Cookies)
-	return statuscode, length, nil, err
+	statuscode, length, header, err := d.http.Get(url, "", d.options.Cookies)
+	return statuscode, length, header, nil, err
+}
+
+// parseRetryAfter reads a Retry-After header into a duration. Only the
+// delay-seconds form is handled - the HTTP-date form exists in the RFC but
+// no target gobuster has hit in practice sends it, and a missing or
+// unparsable header just means "no server-provided delay", not an error.
+func parseRetryAfter(h http.Header) time.Duration {
+	if h == nil {
+		return 0
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
 }
 
 // NewGobusterDir creates a new initialized GobusterDir
@@ -55,8 +105,11 @@ func NewGobusterDir(cont context.Context, globalopts *libgobuster.Options, opts
 	}
 
 	g := GobusterDir{
-		options:    opts,
-		globalopts: globalopts,
+		options:        opts,
+		globalopts:     globalopts,
+		scrapedWords:   make(map[string]struct{}),
+		scrapedByHost:  make(map[string]map[string]struct{}),
+		robotsDisallow: make(map[string][]string),
 	}
 
 	httpOpts := libgobuster.HTTPOptions{
@@ -86,18 +139,29 @@ func (d *GobusterDir) PreRun() error {
 		d.options.URL = fmt.Sprintf("%s/", d.options.URL)
 	}
 
-	_, _, _, err := d.get(d.options.URL, false)
+	_, _, _, _, err := d.get(d.options.URL, false)
 	if err != nil {
 		return fmt.Errorf("unable to connect to %s: %v", d.options.URL, err)
 	}
 
 	guid := uuid.New()
 	url := fmt.Sprintf("%s%s", d.options.URL, guid)
-	wildcardResp, _, _, err := d.get(url, false)
+	wildcardResp, _, _, wildcardBody, err := d.get(url, true)
 	if err != nil {
 		return err
 	}
 
+	// baseline the wildcard response so Run can auto-filter pages that look
+	// like this one (soft-404s that return e.g. 200 with a boilerplate body)
+	// without the user having to pre-compute --fs/--fw themselves
+	if wildcardBody != nil {
+		words, lines, _ := bodyStats(wildcardBody)
+		d.hasBaseline = true
+		d.baselineSize = int64(len(*wildcardBody))
+		d.baselineWords = words
+		d.baselineLines = lines
+	}
+
 	if d.options.StatusCodesBlacklistParsed.Length() > 0 {
 		if !d.options.StatusCodesBlacklistParsed.Contains(*wildcardResp) && !d.options.WildcardForced {
 			return &ErrWildcard{url: url, statusCode: *wildcardResp}
@@ -120,84 +184,128 @@ func (d *GobusterDir) PreRun() error {
 
 // Run is the process implementation of gobusterdir
 func (d *GobusterDir) Run(word string) ([]libgobuster.Result, error) {
+	return d.RunAt(word, d.options.URL, nil)
+}
+
+// RunAt is the libgobuster.RecursionAware implementation of gobusterdir. It's
+// identical to Run except it busts baseURL instead of the configured
+// d.options.URL, which is how --recursive brute forces newly discovered
+// directories without mutating shared state other workers are reading. skip
+// is the set of entities (word+suffix or word.ext) this word already has a
+// final result for from an earlier attempt - a rate-limit retry passes this
+// in so it only redoes whichever request actually got rate limited.
+func (d *GobusterDir) RunAt(word, baseURL string, skip map[string]bool) ([]libgobuster.Result, error) {
+	if baseURL == "" {
+		baseURL = d.options.URL
+	}
+
 	suffix := ""
 	if d.options.UseSlash {
 		suffix = "/"
 	}
 
-	// Try the DIR first
-	url := fmt.Sprintf("%s%s%s", d.options.URL, word, suffix)
-	dirResp, dirSize, _, err := d.get(url, false) // we don't care about the body if we are only checking for dir
-	if err != nil {
-		return nil, err
-	}
 	var ret []libgobuster.Result
-	if dirResp != nil {
-		resultStatus := libgobuster.StatusMissed
-
-		if d.options.StatusCodesBlacklistParsed.Length() > 0 {
-			if !d.options.StatusCodesBlacklistParsed.Contains(*dirResp) {
-				resultStatus = libgobuster.StatusFound
+	var url string
+
+	// Try the DIR first. We always grab the body now (not just for
+	// --wordlist-scrape) since --mc/--ms/--ml/--mw and --fc/--fs/--fl/--fw/--fr
+	// need size/word/line counts to work with.
+	entity := fmt.Sprintf("%s%s", word, suffix)
+	if !skip[entity] {
+		url = fmt.Sprintf("%s%s", baseURL, entity)
+		dirResp, dirSize, dirHeader, dirBody, err := d.get(url, true)
+		if err != nil {
+			return nil, err
+		}
+		if dirResp != nil {
+			words, lines, contentType := bodyStats(dirBody)
+			result := libgobuster.Result{
+				Entity:      entity,
+				URL:         url,
+				StatusCode:  *dirResp,
+				Size:        dirSize,
+				Words:       words,
+				Lines:       lines,
+				ContentType: contentType,
+				Location:    dirHeader.Get("Location"),
+				RetryAfter:  parseRetryAfter(dirHeader),
 			}
-		} else if d.options.StatusCodesParsed.Length() > 0 {
-			if d.options.StatusCodesParsed.Contains(*dirResp) {
-				resultStatus = libgobuster.StatusFound
+			switch {
+			case *dirResp == 429 || *dirResp == 503:
+				// always surfaced, regardless of --mc/--fc/--verbose, so the
+				// engine's rate limiter sees it and retries - the worker
+				// intercepts these before they ever reach the output sink
+				result.Status = libgobuster.StatusMissed
+				ret = append(ret, result)
+			case d.isResult(*dirResp, dirSize, words, lines, dirBody):
+				result.Status = libgobuster.StatusFound
+				ret = append(ret, result)
+			case d.globalopts.Verbose:
+				result.Status = libgobuster.StatusMissed
+				ret = append(ret, result)
 			}
-		} else {
-			return nil, fmt.Errorf("StatusCodes and StatusCodesBlacklist are both not set which should not happen")
-		}
-
-		if resultStatus == libgobuster.StatusFound || d.globalopts.Verbose {
-			ret = append(ret, libgobuster.Result{
-				Entity:     fmt.Sprintf("%s%s", word, suffix),
-				StatusCode: *dirResp,
-				Size:       dirSize,
-				Status:     resultStatus,
-			})
 		}
 	}
 
 	// Follow up with files using each ext.
 	for ext := range d.options.ExtensionsParsed.Set {
 		file := fmt.Sprintf("%s.%s", word, ext)
-		url = fmt.Sprintf("%s%s", d.options.URL, file)
-		fileResp, fileSize, body, err := d.get(url, d.options.ScrapeWords > 0) // we now care about this flag value for files
-
-		// bit annoying to have this check, but just incase we try to scrape and get null bodies back
-		if body == nil && d.options.ScrapeWords > 0 {
-			return nil, fmt.Errorf("Response body was nil, even though we want to scrape words? Edge case?")
+		if skip[file] {
+			continue
 		}
-
+		url = fmt.Sprintf("%s%s", baseURL, file)
+		fileResp, fileSize, fileHeader, body, err := d.get(url, true)
 		if err != nil {
 			return nil, err
 		}
 
 		if fileResp != nil {
-			resultStatus := libgobuster.StatusMissed
-
-			if d.options.StatusCodesBlacklistParsed.Length() > 0 {
-				if !d.options.StatusCodesBlacklistParsed.Contains(*fileResp) {
-					resultStatus = libgobuster.StatusFound
-				}
-			} else if d.options.StatusCodesParsed.Length() > 0 {
-				if d.options.StatusCodesParsed.Contains(*fileResp) {
-					resultStatus = libgobuster.StatusFound
-				}
-			} else {
-				return nil, fmt.Errorf("StatusCodes and StatusCodesBlacklist are both not set which should not happen")
+			words, lines, contentType := bodyStats(body)
+			retryAfter := parseRetryAfter(fileHeader)
+			location := fileHeader.Get("Location")
+
+			if *fileResp == 429 || *fileResp == 503 {
+				// always surfaced, regardless of --mc/--fc/--verbose, so the
+				// engine's rate limiter sees it and retries - the worker
+				// intercepts these before they ever reach the output sink
+				ret = append(ret, libgobuster.Result{
+					Entity:      file,
+					URL:         url,
+					StatusCode:  *fileResp,
+					Size:        fileSize,
+					Status:      libgobuster.StatusMissed,
+					Words:       words,
+					Lines:       lines,
+					ContentType: contentType,
+					Location:    location,
+					RetryAfter:  retryAfter,
+				})
+				continue
 			}
 
-			if resultStatus == libgobuster.StatusFound || d.globalopts.Verbose {
-				// are we wanting to save the request bodies for grabbing unique words?
-				if d.options.ScrapeWords > 0 {
-					d.ScrapeUniqueWords(body, word)
+			isHit := d.isResult(*fileResp, fileSize, words, lines, body)
+
+			if isHit || d.globalopts.Verbose {
+				resultStatus := libgobuster.StatusMissed
+				if isHit {
+					resultStatus = libgobuster.StatusFound
+					// are we wanting to save the request bodies for grabbing unique words?
+					if d.options.ScrapeWords > 0 {
+						d.ScrapeUniqueWords(body, url)
+					}
 				}
 
 				ret = append(ret, libgobuster.Result{
-					Entity:     file,
-					StatusCode: *fileResp,
-					Size:       fileSize,
-					Status:     resultStatus,
+					Entity:      file,
+					URL:         url,
+					StatusCode:  *fileResp,
+					Size:        fileSize,
+					Status:      resultStatus,
+					Words:       words,
+					Lines:       lines,
+					ContentType: contentType,
+					Location:    location,
+					RetryAfter:  retryAfter,
 				})
 			}
 		}
@@ -205,68 +313,389 @@ func (d *GobusterDir) Run(word string) ([]libgobuster.Result, error) {
 	return ret, nil
 }
 
-// ScrapeUniqueWords obtains all unique words from the downloaded page to use as a wordlist
-func (d *GobusterDir) ScrapeUniqueWords(body *[]byte, urlword string) {
-	minlength := d.options.ScrapeWords                        // this should always be greater than 0 if we are here
-	charblacklist := "!@£$%^&*()#€-=_+;:'\"\\/?<>,.`~|§±[]}{" // lol? used a bit further down - this will probably be dynamic based on usage results
-
-	doc := soup.HTMLParse(string(*body)) // use 'soup', not 100% checked the codebase to check it's okay but seems fine
-	alltext := doc.FullText()
-
-	allwords := []string{}                // bank all our found words after splitting and finding legal entries
-	lines := strings.Split(alltext, "\n") // first split as the 'soup' result is a single string
-
-	// probably not optimised as much as it could be (or at all really). Will update/change if I find nicer ways to do all this
-	for _, l := range lines {
-		if l != "" {
-			words := strings.Split(l, " ") // split the line by spaces
-			for _, w := range words {
-				// this feels meh, but we need to strip anything that isn't a letter or number from here (comma, fullstops, etc)
-				for _, char := range w {
-					if strings.Contains(charblacklist, strings.ToLower(string(char))) {
-						w = strings.Replace(w, string(char), "", -1)
-					}
-				}
+// isResult decides whether a response counts as a hit. Status codes are still
+// the baseline (via StatusCodes/StatusCodesBlacklist), but --mc/--ms/--ml/--mw
+// can additionally require an explicit match and --fc/--fs/--fl/--fw/--fr can
+// drop a response that would otherwise match. A body within
+// --auto-filter-threshold bytes/words of the wildcard baseline from PreRun is
+// filtered the same way a soft-404 explicitly matched by --fs/--fw would be.
+func (d *GobusterDir) isResult(statusCode int, size *int64, words, lines int, body *[]byte) bool {
+	statusMatches := false
+	if d.options.StatusCodesBlacklistParsed.Length() > 0 {
+		statusMatches = !d.options.StatusCodesBlacklistParsed.Contains(statusCode)
+	} else if d.options.StatusCodesParsed.Length() > 0 {
+		statusMatches = d.options.StatusCodesParsed.Contains(statusCode)
+	}
+	if !statusMatches {
+		return false
+	}
 
-				// now the word has been 'cleansed' from characters (blacklist based), we check the minlength of the entry
-				if len(w) >= minlength {
-					allwords = append(allwords, w)
-				}
+	if d.options.MatchCodesParsed.Length() > 0 && !d.options.MatchCodesParsed.Contains(statusCode) {
+		return false
+	}
+	if len(d.options.MatchSizes) > 0 && (size == nil || !containsInt64(d.options.MatchSizes, *size)) {
+		return false
+	}
+	if len(d.options.MatchWords) > 0 && !containsInt(d.options.MatchWords, words) {
+		return false
+	}
+	if len(d.options.MatchLines) > 0 && !containsInt(d.options.MatchLines, lines) {
+		return false
+	}
+
+	if d.options.FilterCodesParsed.Length() > 0 && d.options.FilterCodesParsed.Contains(statusCode) {
+		return false
+	}
+	if size != nil && containsInt64(d.options.FilterSizes, *size) {
+		return false
+	}
+	if containsInt(d.options.FilterWords, words) {
+		return false
+	}
+	if containsInt(d.options.FilterLines, lines) {
+		return false
+	}
+	if d.options.FilterRegexParsed != nil && body != nil && d.options.FilterRegexParsed.Match(*body) {
+		return false
+	}
+
+	if d.hasBaseline && size != nil {
+		threshold := d.options.AutoFilterThreshold
+		if absInt64(*size-d.baselineSize) <= threshold && absInt(words-d.baselineWords) <= int(threshold) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// joinInts renders a --mw/--ml/--fw/--fl set for GetConfigString's banner.
+func joinInts(vals []int) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// joinInt64s renders a --ms/--fs set for GetConfigString's banner.
+func joinInt64s(vals []int64) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ShouldRecurse is the libgobuster.RecursionAware implementation of gobusterdir.
+// A hit only gets brute forced again if --recursive is on, it's a directory
+// (entities for files always carry an extension), it matched rather than
+// missed, and it's not excluded by --recursion-status.
+func (d *GobusterDir) ShouldRecurse(r libgobuster.Result) (string, bool) {
+	if !d.globalopts.Recursive || r.Status != libgobuster.StatusFound {
+		return "", false
+	}
+
+	if strings.Contains(r.Entity, ".") {
+		return "", false
+	}
+
+	if d.options.RecursionStatusCodesParsed.Length() > 0 && !d.options.RecursionStatusCodesParsed.Contains(r.StatusCode) {
+		return "", false
+	}
+
+	// r.URL is already baseURL+entity, i.e. the base this hit was actually
+	// found under - using it (rather than rebuilding from d.options.URL) is
+	// what makes depth >= 2 nest under the right parent instead of re-rooting
+	// every recursion pass at the site root.
+	base := strings.TrimSuffix(r.URL, "/")
+	return base + "/", true
+}
+
+// skippedScrapeTags are elements whose text content isn't page content -
+// script/style bodies and comments would otherwise pollute the wordlist with
+// JS identifiers and CSS class names.
+var skippedScrapeTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+}
+
+// ScrapeUniqueWords streams body through an HTML tokenizer (rather than
+// parsing the whole DOM) and merges every legal word into the scan-wide
+// dedup set, skipping pageURL entirely if a
+// <meta name="robots" content="nofollow"> says it isn't meant to be crawled,
+// or, when ScrapeHonorRobots is set, if robots.txt disallows it.
+func (d *GobusterDir) ScrapeUniqueWords(body *[]byte, pageURL string) {
+	if d.options.ScrapeHonorRobots && d.isDisallowedByRobots(pageURL) {
+		return
+	}
+
+	words, nofollow := tokenizeWords(*body)
+	if nofollow {
+		return
+	}
+
+	host := ""
+	if u, err := url.Parse(pageURL); err == nil {
+		host = u.Host
+	}
+
+	d.scrapeMu.Lock()
+	defer d.scrapeMu.Unlock()
+	for _, w := range words {
+		if !d.isLegalScrapeWord(w) {
+			continue
+		}
+		if d.options.ScrapeLowercase {
+			w = strings.ToLower(w)
+		}
+		d.scrapedWords[w] = struct{}{}
+		if d.options.ScrapePerHost && host != "" {
+			if d.scrapedByHost[host] == nil {
+				d.scrapedByHost[host] = make(map[string]struct{})
+			}
+			d.scrapedByHost[host][w] = struct{}{}
+		}
+	}
+}
+
+// isLegalScrapeWord applies the --scrape-min/--scrape-max length bounds and
+// the stopword list.
+func (d *GobusterDir) isLegalScrapeWord(w string) bool {
+	if len(w) < d.options.ScrapeWords {
+		return false
+	}
+	if d.options.ScrapeWordsMax > 0 && len(w) > d.options.ScrapeWordsMax {
+		return false
+	}
+	if _, stop := d.options.ScrapeStopwords[strings.ToLower(w)]; stop {
+		return false
+	}
+	return true
+}
+
+// tokenizeWords walks body with html.NewTokenizer, collecting the words out
+// of every text node outside a skipped tag, and reports whether a
+// <meta name="robots" content="nofollow"> was seen along the way.
+func tokenizeWords(body []byte) (words []string, nofollow bool) {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	skipDepth := 0
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return words, nofollow
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if skippedScrapeTags[tok.Data] {
+				skipDepth++
+			}
+			if tok.Data == "meta" && isNofollowMeta(tok) {
+				nofollow = true
+			}
+
+		case html.EndTagToken:
+			if skippedScrapeTags[z.Token().Data] && skipDepth > 0 {
+				skipDepth--
+			}
+
+		case html.CommentToken:
+			// comments are never page content, regardless of skipDepth
+
+		case html.TextToken:
+			if skipDepth == 0 {
+				words = append(words, splitWords(string(z.Text()))...)
 			}
 		}
 	}
+}
 
-	// now we want to loop and make sure we only get a single instance of each word to remove dupes
-	finalwords := []string{}
-	for _, w := range allwords {
-		if contains(finalwords, w) {
+// isNofollowMeta reports whether tok is <meta name="robots" content="...nofollow...">.
+func isNofollowMeta(tok html.Token) bool {
+	var name, content string
+	for _, a := range tok.Attr {
+		switch a.Key {
+		case "name":
+			name = strings.ToLower(a.Val)
+		case "content":
+			content = strings.ToLower(a.Val)
+		}
+	}
+	return name == "robots" && strings.Contains(content, "nofollow")
+}
+
+// splitWords is a unicode-aware word boundary splitter: anything that isn't a
+// letter or digit ends the current word, rather than the old hardcoded
+// punctuation blacklist.
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			cur = append(cur, r)
 			continue
 		}
-		finalwords = append(finalwords, w)
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = cur[:0]
+		}
+	}
+	if len(cur) > 0 {
+		words = append(words, string(cur))
 	}
+	return words
+}
 
-	// blit the output to disk (output directory, does this exist elsewhere in the project?)
-	// maybe use writeToFile in gobuster.go?
-	targetwritename := urlword + ".txt"
-	f, err := os.Create("output/" + targetwritename) // output dir is checked at start of Run() this should exist
+// isDisallowedByRobots fetches (and caches, per host) robots.txt and reports
+// whether pageURL's path is disallowed for User-agent: *.
+func (d *GobusterDir) isDisallowedByRobots(pageURL string) bool {
+	u, err := url.Parse(pageURL)
 	if err != nil {
-		fmt.Println(err)
-		f.Close()
-		return
+		return false
 	}
 
-	for _, fw := range finalwords {
-		fmt.Fprintln(f, fw)
-		if err != nil {
-			fmt.Println(err)
-			return
+	disallow := d.robotsRules(u)
+	for _, prefix := range disallow {
+		if prefix != "" && strings.HasPrefix(u.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *GobusterDir) robotsRules(u *url.URL) []string {
+	d.robotsMu.Lock()
+	defer d.robotsMu.Unlock()
+
+	if rules, ok := d.robotsDisallow[u.Host]; ok {
+		return rules
+	}
+
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host)
+	_, _, _, body, err := d.get(robotsURL, true)
+	if err != nil || body == nil {
+		d.robotsDisallow[u.Host] = nil
+		return nil
+	}
+
+	rules := parseRobotsDisallow(*body)
+	d.robotsDisallow[u.Host] = rules
+	return rules
+}
+
+// parseRobotsDisallow is a deliberately small robots.txt reader: the
+// Disallow lines under every "User-agent: *" (or "User-agent: gobuster")
+// group. It doesn't handle Allow overrides or wildcards - good enough to stop
+// scraping obviously-private paths without gobuster becoming a robots.txt
+// parser library.
+func parseRobotsDisallow(body []byte) []string {
+	var rules []string
+	relevant := false
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch field {
+		case "user-agent":
+			relevant = value == "*" || strings.EqualFold(value, "gobuster")
+		case "disallow":
+			if relevant && value != "" {
+				rules = append(rules, value)
+			}
+		}
+	}
+	return rules
+}
+
+// Finish is the libgobuster.Finisher implementation of gobusterdir: it flushes
+// the merged --wordlist-scrape output once per scan instead of once per
+// request. output/words.txt always gets the full deduplicated set; with
+// --scrape-per-host, output/<host>.txt gets that host's subset too.
+func (d *GobusterDir) Finish() error {
+	if d.options.ScrapeWords == 0 {
+		return nil
+	}
+
+	d.scrapeMu.Lock()
+	defer d.scrapeMu.Unlock()
+
+	if err := writeWordlist("output/words.txt", d.scrapedWords); err != nil {
+		return err
+	}
+
+	if d.options.ScrapePerHost {
+		for host, words := range d.scrapedByHost {
+			if err := writeWordlist(fmt.Sprintf("output/%s.txt", host), words); err != nil {
+				return err
+			}
 		}
 	}
-	err = f.Close()
+
+	return nil
+}
+
+func writeWordlist(path string, words map[string]struct{}) error {
+	sorted := make([]string, 0, len(words))
+	for w := range words {
+		sorted = append(sorted, w)
+	}
+	sort.Strings(sorted)
+
+	f, err := os.Create(path)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	defer f.Close()
+
+	for _, w := range sorted {
+		if _, err := fmt.Fprintln(f, w); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // ResultToString is the to string implementation of gobusterdir
@@ -341,6 +770,12 @@ func (d *GobusterDir) GetConfigString() (string, error) {
 		}
 	}
 
+	if d.globalopts.Rate > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Rate limit:\t%.2f req/s\n", d.globalopts.Rate); err != nil {
+			return "", err
+		}
+	}
+
 	wordlist := "stdin (pipe)"
 	if d.globalopts.Wordlist != "-" {
 		wordlist = d.globalopts.Wordlist
@@ -349,6 +784,12 @@ func (d *GobusterDir) GetConfigString() (string, error) {
 		return "", err
 	}
 
+	if d.globalopts.Resume != "" {
+		if _, err := fmt.Fprintf(tw, "[+] Resume file:\t%s (mode: %s)\n", d.globalopts.Resume, d.globalopts.ResumeMode); err != nil {
+			return "", err
+		}
+	}
+
 	if o.StatusCodesBlacklistParsed.Length() > 0 {
 		if _, err := fmt.Fprintf(tw, "[+] Negative Status codes:\t%s\n", o.StatusCodesBlacklistParsed.Stringify()); err != nil {
 			return "", err
@@ -359,6 +800,68 @@ func (d *GobusterDir) GetConfigString() (string, error) {
 		}
 	}
 
+	if d.globalopts.Recursive {
+		if _, err := fmt.Fprintf(tw, "[+] Recursive:\ttrue (max depth: %d)\n", d.globalopts.MaxDepth); err != nil {
+			return "", err
+		}
+		if o.RecursionStatusCodesParsed.Length() > 0 {
+			if _, err := fmt.Fprintf(tw, "[+] Recursion status codes:\t%s\n", o.RecursionStatusCodesParsed.Stringify()); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if o.MatchCodesParsed.Length() > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Match codes:\t%s\n", o.MatchCodesParsed.Stringify()); err != nil {
+			return "", err
+		}
+	}
+	if len(o.MatchSizes) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Match sizes:\t%s\n", joinInt64s(o.MatchSizes)); err != nil {
+			return "", err
+		}
+	}
+	if len(o.MatchWords) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Match word counts:\t%s\n", joinInts(o.MatchWords)); err != nil {
+			return "", err
+		}
+	}
+	if len(o.MatchLines) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Match line counts:\t%s\n", joinInts(o.MatchLines)); err != nil {
+			return "", err
+		}
+	}
+	if o.FilterCodesParsed.Length() > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Filter codes:\t%s\n", o.FilterCodesParsed.Stringify()); err != nil {
+			return "", err
+		}
+	}
+	if len(o.FilterSizes) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Filter sizes:\t%s\n", joinInt64s(o.FilterSizes)); err != nil {
+			return "", err
+		}
+	}
+	if len(o.FilterWords) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Filter word counts:\t%s\n", joinInts(o.FilterWords)); err != nil {
+			return "", err
+		}
+	}
+	if len(o.FilterLines) > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Filter line counts:\t%s\n", joinInts(o.FilterLines)); err != nil {
+			return "", err
+		}
+	}
+	if o.FilterRegexParsed != nil {
+		if _, err := fmt.Fprintf(tw, "[+] Filter regex:\t%s\n", o.FilterRegexParsed.String()); err != nil {
+			return "", err
+		}
+	}
+	if o.AutoFilterThreshold > 0 {
+		if _, err := fmt.Fprintf(tw, "[+] Auto-filter threshold:\t%d\n", o.AutoFilterThreshold); err != nil {
+			return "", err
+		}
+	}
+
 	if o.Proxy != "" {
 		if _, err := fmt.Fprintf(tw, "[+] Proxy:\t%s\n", o.Proxy); err != nil {
 			return "", err
@@ -435,6 +938,12 @@ func (d *GobusterDir) GetConfigString() (string, error) {
 		}
 	}
 
+	if d.globalopts.OutputFile != "" {
+		if _, err := fmt.Fprintf(tw, "[+] Output file:\t%s (format: %s)\n", d.globalopts.OutputFile, d.globalopts.Format); err != nil {
+			return "", err
+		}
+	}
+
 	if err := tw.Flush(); err != nil {
 		return "", fmt.Errorf("error on tostring: %v", err)
 	}
@@ -446,13 +955,21 @@ func (d *GobusterDir) GetConfigString() (string, error) {
 	return strings.TrimSpace(buffer.String()), nil
 }
 
-// used with char blacklist above (ref: https://ispycode.com/GO/Collections/Arrays/Check-if-item-is-in-array)
-// TODO: move this to a util script or something if one exists?
-func contains(arr []string, str string) bool {
-	for _, a := range arr {
-		if a == str {
-			return true
-		}
-	}
-	return false
+// bodyStats derives the word/line counts and content type the structured
+// output sinks (see libgobuster.ResultSink) want from a fetched body. The
+// Location header (Result.Location) is read separately in RunAt, straight
+// off the response headers d.get already returns.
+//
+// lines counts newline characters the same way `wc -l` does, so a value a
+// user derives from `curl ... | wc -l` lines up with --ml/--fl: a body with
+// no trailing newline has one fewer "line" than its number of text rows, and
+// an empty body is 0, not 1.
+func bodyStats(body *[]byte) (words, lines int, contentType string) {
+	if body == nil {
+		return 0, 0, ""
+	}
+	contentType = http.DetectContentType(*body)
+	words = len(bytes.Fields(*body))
+	lines = bytes.Count(*body, []byte("\n"))
+	return words, lines, contentType
 }
@@ -0,0 +1,133 @@
+package gobusterdir
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		h    http.Header
+		want time.Duration
+	}{
+		{"nil header", nil, 0},
+		{"missing header", http.Header{}, 0},
+		{"integer seconds", http.Header{"Retry-After": []string{"5"}}, 5 * time.Second},
+		{"zero seconds", http.Header{"Retry-After": []string{"0"}}, 0},
+		{"negative seconds", http.Header{"Retry-After": []string{"-1"}}, 0},
+		{"http-date form", http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.h); got != tt.want {
+				t.Errorf("parseRetryAfter(%v) = %v, want %v", tt.h, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"hello world", []string{"hello", "world"}},
+		{"foo-bar_baz.qux", []string{"foo", "bar", "baz", "qux"}},
+		{"  leading and trailing  ", []string{"leading", "and", "trailing"}},
+		{"café 123", []string{"café", "123"}},
+	}
+	for _, tt := range tests {
+		if got := splitWords(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeWords(t *testing.T) {
+	body := []byte(`<html><body>
+		<!-- admin-secret should not be scraped -->
+		<script>var adminToken = "skip-me";</script>
+		<style>.adminClass { color: red }</style>
+		<p>visible words here</p>
+	</body></html>`)
+
+	words, nofollow := tokenizeWords(body)
+
+	for _, w := range words {
+		if w == "admin" || w == "secret" || w == "adminToken" || w == "adminClass" {
+			t.Errorf("tokenizeWords scraped a word it should have skipped: %q in %v", w, words)
+		}
+	}
+
+	found := map[string]bool{}
+	for _, w := range words {
+		found[w] = true
+	}
+	for _, want := range []string{"visible", "words", "here"} {
+		if !found[want] {
+			t.Errorf("tokenizeWords(%s) = %v, missing expected word %q", body, words, want)
+		}
+	}
+	if nofollow {
+		t.Errorf("tokenizeWords(%s) nofollow = true, want false", body)
+	}
+}
+
+func TestTokenizeWords_Nofollow(t *testing.T) {
+	body := []byte(`<html><head><meta name="robots" content="noindex, nofollow"></head></html>`)
+	_, nofollow := tokenizeWords(body)
+	if !nofollow {
+		t.Errorf("tokenizeWords(%s) nofollow = false, want true", body)
+	}
+}
+
+func TestParseRobotsDisallow(t *testing.T) {
+	body := []byte(`# comment line should be ignored
+User-agent: other-bot
+Disallow: /other-only/
+
+User-agent: *
+Disallow: /admin/
+Disallow: /secret/
+Disallow:
+
+User-agent: GoBuster
+Disallow: /gobuster-only/
+`)
+
+	got := parseRobotsDisallow(body)
+	want := []string{"/admin/", "/secret/", "/gobuster-only/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRobotsDisallow(%s) = %v, want %v", body, got, want)
+	}
+}
+
+func TestBodyStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      *[]byte
+		wantLines int
+	}{
+		{"nil body", nil, 0},
+		{"empty body", &[]byte{}, 0},
+		{"trailing newline", bytesPtr("a\nb\n"), 2},
+		{"no trailing newline", bytesPtr("a\nb"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lines, _ := bodyStats(tt.body)
+			if lines != tt.wantLines {
+				t.Errorf("bodyStats(%v) lines = %d, want %d", tt.body, lines, tt.wantLines)
+			}
+		})
+	}
+}
+
+func bytesPtr(s string) *[]byte {
+	b := []byte(s)
+	return &b
+}
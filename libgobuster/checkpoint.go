@@ -0,0 +1,132 @@
+package libgobuster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// checkpointState is the on-disk snapshot --resume reads and writes. It's one
+// (indented) JSON document rather than a journal - gobuster scans are bounded by
+// wordlist size, not an open-ended event stream, so there's nothing a journal
+// would buy that periodic overwrite doesn't.
+type checkpointState struct {
+	ConfigHash     string         `json:"config_hash"`
+	Wordlist       string         `json:"wordlist"`
+	WordlistOffset int            `json:"wordlist_offset"`
+	RequestsIssued int            `json:"requests_issued"`
+	Queue          []recursionJob `json:"queue,omitempty"`
+}
+
+// configHash fingerprints the plugin's config together with the wordlist
+// path, so a --resume file only reattaches to the run it was written for.
+// --resume-mode=append skips this check entirely and trusts the file as-is.
+func (g *Gobuster) configHash() (string, error) {
+	cfg, err := g.plugin.GetConfigString()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(g.Opts.Wordlist + "\x00" + cfg))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// loadCheckpoint reads the --resume state file, if one was given. A missing
+// file isn't an error - it just means this is a fresh run that happens to be
+// checkpointing as it goes.
+func (g *Gobuster) loadCheckpoint() (*checkpointState, error) {
+	if g.Opts.Resume == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(g.Opts.Resume)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read resume file: %v", err)
+	}
+
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse resume file: %v", err)
+	}
+
+	if g.Opts.ResumeMode != "append" {
+		hash, err := g.configHash()
+		if err != nil {
+			return nil, err
+		}
+		if state.ConfigHash != hash {
+			return nil, fmt.Errorf("resume file %s was recorded with different options; rerun with --resume-mode append to use it anyway", g.Opts.Resume)
+		}
+	}
+
+	return &state, nil
+}
+
+// saveCheckpoint overwrites the --resume state file with the current
+// progress. It writes to a temp file and renames over the real one so a crash
+// mid-write can never leave a half-written, unparsable checkpoint behind.
+func (g *Gobuster) saveCheckpoint() error {
+	if g.Opts.Resume == "" {
+		return nil
+	}
+
+	hash, err := g.configHash()
+	if err != nil {
+		return err
+	}
+
+	g.mu.RLock()
+	state := checkpointState{
+		ConfigHash: hash,
+		Wordlist:   g.Opts.Wordlist,
+		// WordlistOffset must only count the depth-0/baseURL-"" pass, not
+		// recursion sub-jobs or rate-limit retry re-deliveries - runJob uses
+		// it to fast-forward that one pass, and either of those would make
+		// it overcount and skip real, never-issued wordlist lines on resume.
+		WordlistOffset: g.depth0Issued,
+		RequestsIssued: g.requestsIssued,
+	}
+	g.mu.RUnlock()
+
+	g.queueMu.Lock()
+	state.Queue = append([]recursionJob{}, g.pendingJobs...)
+	g.queueMu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := g.Opts.Resume + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, g.Opts.Resume)
+}
+
+// checkpointLoop saves the checkpoint on a fixed interval until stop fires,
+// so --resume never loses more than ~interval's worth of requests to a
+// Ctrl-C or a crash.
+func (g *Gobuster) checkpointLoop(interval time.Duration, stop <-chan struct{}) {
+	if g.Opts.Resume == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-g.context.Done():
+			return
+		case <-ticker.C:
+			if err := g.saveCheckpoint(); err != nil {
+				g.LogError.Printf("failed to write checkpoint: %v", err)
+			}
+		}
+	}
+}
@@ -21,18 +21,105 @@ type ProcessFunc func(*Gobuster, string) ([]Result, error)
 // ResultToStringFunc is the "to string" function prototype for implementations
 type ResultToStringFunc func(*Gobuster, *Result) (*string, error)
 
+// recursionJob describes a single brute-force pass: run the wordlist against
+// BaseURL, at the given recursion Depth. The zero-value BaseURL ("") means
+// "use the plugin's own configured target" - that's what the first, non-recursive
+// pass uses. Fields are exported (with json tags) so a --resume checkpoint can
+// actually marshal the pending queue instead of silently writing "{}".
+type recursionJob struct {
+	BaseURL string `json:"base_url"`
+	Depth   int    `json:"depth"`
+}
+
+// wordJob is a single unit of work handed to a worker: one wordlist entry,
+// tagged with the job it came from so results can be routed back into
+// maybeRecurse at the right depth. retry marks a re-delivery of a word that
+// came back 429/503 rather than a genuinely new word, so counters that track
+// real wordlist progress (depth0Issued) don't double-count it. skipEntities
+// is the set of r.Entity values this word already produced a final result
+// for on an earlier attempt, so a retry only redoes the request(s) that
+// actually got rate limited.
+type wordJob struct {
+	word         string
+	baseURL      string
+	depth        int
+	retry        bool
+	skipEntities map[string]bool
+}
+
+// Finisher is implemented by plugins that have buffered work to flush once a
+// scan's last result has been produced - gobusterdir uses it to write its
+// merged scraped-words file once instead of once per request.
+type Finisher interface {
+	Finish() error
+}
+
+// RecursionAware is implemented by plugins that can be re-run against a base
+// other than the one they were originally configured with. Gobuster uses it to
+// drive --recursive: whenever a result qualifies, ShouldRecurse hands back the
+// new base to brute force and the engine queues it as a job at depth+1.
+//
+// skip lets the caller re-run RunAt for a word without redoing entities it
+// already has a final (non-429/503) Result for - a rate-limit retry only
+// needs to redo whichever single request actually got rate limited, not every
+// extension the word checks.
+type RecursionAware interface {
+	RunAt(word, baseURL string, skip map[string]bool) ([]Result, error)
+	ShouldRecurse(r Result) (baseURL string, recurse bool)
+}
+
 // Gobuster is the main object when creating a new run
 type Gobuster struct {
 	Opts             *Options
 	context          context.Context
 	requestsExpected int
 	requestsIssued   int
+	requestsQueued   int
 	mu               *sync.RWMutex
 	plugin           GobusterPlugin
 	resultChan       chan Result
 	errorChan        chan error
 	LogInfo          *log.Logger
 	LogError         *log.Logger
+	visited          map[string]bool
+	visitedMu        sync.Mutex
+	queueWG          sync.WaitGroup
+	jobChan          chan wordJob
+
+	// resumeOffset is how many wordlist lines of the depth-0/baseURL-""
+	// pass a loaded checkpoint says are already issued; runJob fast-forwards
+	// past them instead of reissuing requests --resume is meant to avoid.
+	resumeOffset int
+	// depth0Issued counts only genuinely new words dequeued for the
+	// depth-0/baseURL-"" pass - unlike requestsIssued (which also counts
+	// recursion sub-jobs and rate-limit retry re-deliveries), this is what
+	// saveCheckpoint writes out as WordlistOffset, so resuming never
+	// fast-forwards past lines that were never actually issued.
+	depth0Issued int
+	pendingJobs  []recursionJob
+	queueMu      sync.Mutex
+	jobProgress  map[recursionJob]*recursionProgress
+
+	// words is the wordlist, read into memory once by loadWordlist. Every
+	// runJob call - the initial pass and every recursion step - shares this
+	// same slice instead of re-reading the wordlist file(s) from disk.
+	words []string
+
+	rateLimiter *RateLimiter
+}
+
+// recursionProgress tracks, for one in-flight recursion job, how many of its
+// words a worker has accepted off jobChan but not yet fully resolved
+// (including words still sitting in jobChan's buffer and words mid
+// rate-limit-retry backoff), and whether runJob has finished feeding the
+// wordlist in. A job is only dropped from pendingJobs - and so stops being
+// recorded in the next --resume checkpoint as in-flight - once feedingDone
+// is true and remaining has drained to zero; merely finishing the feed loop
+// isn't enough, since up to Opts.Threads words can still be buffered in
+// jobChan or waiting out a 429/503 backoff at that point.
+type recursionProgress struct {
+	remaining   int
+	feedingDone bool
 }
 
 // NewGobuster returns a new Gobuster object
@@ -46,6 +133,7 @@ func NewGobuster(c context.Context, opts *Options, plugin GobusterPlugin) (*Gobu
 	g.errorChan = make(chan error)
 	g.LogInfo = log.New(os.Stdout, "", log.LstdFlags)
 	g.LogError = log.New(os.Stderr, "[ERROR] ", log.LstdFlags)
+	g.rateLimiter = NewRateLimiter(opts.Rate)
 
 	return &g, nil
 }
@@ -70,10 +158,16 @@ func (g *Gobuster) incrementRequests() {
 func (g *Gobuster) PrintProgress() {
 	if !g.Opts.Quiet && !g.Opts.NoProgress {
 		g.mu.RLock()
-		if g.Opts.Wordlist == "-" {
+		switch {
+		case g.Opts.Wordlist == "-":
 			fmt.Fprintf(os.Stderr, "\rProgress: %d", g.requestsIssued)
+		case g.Opts.Recursive:
+			// the plan keeps growing as recursion finds new directories, so there's
+			// no fixed total to show a percentage against - show what's been
+			// issued against what's been queued so far instead
+			fmt.Fprintf(os.Stderr, "\rProgress: %d / %d (issued/queued)", g.requestsIssued, g.requestsQueued)
 			// only print status if we already read in the wordlist
-		} else if g.requestsExpected > 0 {
+		case g.requestsExpected > 0:
 			fmt.Fprintf(os.Stderr, "\rProgress: %d / %d (%3.2f%%)", g.requestsIssued, g.requestsExpected, float32(g.requestsIssued)*100.0/float32(g.requestsExpected))
 		}
 		g.mu.RUnlock()
@@ -85,37 +179,92 @@ func (g *Gobuster) ClearProgress() {
 	fmt.Fprint(os.Stderr, resetTerminal())
 }
 
-func (g *Gobuster) worker(wordChan <-chan string, wg *sync.WaitGroup) {
+func (g *Gobuster) worker(jobChan <-chan wordJob, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
 		select {
 		case <-g.context.Done():
 			return
-		case word, ok := <-wordChan:
+		case job, ok := <-jobChan:
 			// worker finished
 			if !ok {
 				return
 			}
 			g.incrementRequests()
+			if job.depth == 0 && job.baseURL == "" && !job.retry {
+				g.mu.Lock()
+				g.depth0Issued++
+				g.mu.Unlock()
+			}
+			jobKey := recursionJob{BaseURL: job.baseURL, Depth: job.depth}
 
-			wordCleaned := strings.TrimSpace(word)
+			wordCleaned := strings.TrimSpace(job.word)
 			// Skip "comment" (starts with #), as well as empty lines
 			if strings.HasPrefix(wordCleaned, "#") || len(wordCleaned) == 0 {
+				g.completeWord(jobKey)
 				break
 			}
 
+			if err := g.rateLimiter.Wait(g.context); err != nil {
+				return
+			}
+
 			// Mode-specific processing
-			res, err := g.plugin.Run(wordCleaned)
+			res, err := g.runPlugin(wordCleaned, job.baseURL, job.skipEntities)
 			if err != nil {
 				// do not exit and continue
+				g.completeWord(jobKey)
 				g.errorChan <- err
 				continue
-			} else {
-				for _, r := range res {
-					g.resultChan <- r
+			}
+
+			backoff := time.Duration(0)
+			retry := false
+			done := job.skipEntities
+			for _, r := range res {
+				g.rateLimiter.OnResult(r, r.RetryAfter)
+				if r.StatusCode == 429 || r.StatusCode == 503 {
+					retry = true
+					if r.RetryAfter > backoff {
+						backoff = r.RetryAfter
+					}
+					// don't emit or mark done yet - it hasn't actually succeeded
+					continue
+				}
+				g.resultChan <- r
+				g.maybeRecurse(r, job.depth)
+				if done == nil {
+					done = make(map[string]bool, len(res))
 				}
+				done[r.Entity] = true
 			}
 
+			// a rate-limited/overloaded target gets just the entities that
+			// actually got rate limited re-queued after the backoff, instead
+			// of the whole word (which would replay and re-emit every
+			// extension that already succeeded)
+			if retry {
+				retryJob := job
+				retryJob.retry = true
+				retryJob.skipEntities = done
+				g.queueWG.Add(1)
+				go func(job wordJob, backoff time.Duration) {
+					defer g.queueWG.Done()
+					select {
+					case <-g.context.Done():
+						return
+					case <-time.After(backoff):
+					}
+					select {
+					case <-g.context.Done():
+					case g.jobChan <- job:
+					}
+				}(retryJob, backoff)
+				continue
+			}
+
+			g.completeWord(jobKey)
+
 			select {
 			case <-g.context.Done():
 			case <-time.After(g.Opts.Delay):
@@ -124,95 +273,206 @@ func (g *Gobuster) worker(wordChan <-chan string, wg *sync.WaitGroup) {
 	}
 }
 
-// getWordlist() converted to return multiple scanners instead of one. This allows a directory of wordlists to be loaded \o/
-func (g *Gobuster) getWordlist() (*[]bufio.Scanner, error) {
-	if g.Opts.Wordlist == "-" {
-		// Read directly from stdin
-		// return bufio.NewScanner(os.Stdin)
-		scanner := bufio.NewScanner(os.Stdin)
-		scannerarray := []bufio.Scanner{}
-		scannerarray = append(scannerarray, *scanner)
-		return &scannerarray, nil
+// runPlugin dispatches a single word to the plugin. If baseURL is set and the
+// plugin supports RecursionAware, it's run against that base instead of the
+// plugin's own configured target. skip is only honoured by RecursionAware
+// plugins; a plain Run(word) always redoes the whole word since it has no
+// way to skip individual entities.
+func (g *Gobuster) runPlugin(word, baseURL string, skip map[string]bool) ([]Result, error) {
+	if baseURL == "" && len(skip) == 0 {
+		return g.plugin.Run(word)
+	}
+	rp, ok := g.plugin.(RecursionAware)
+	if !ok {
+		return g.plugin.Run(word)
 	}
+	return rp.RunAt(word, baseURL, skip)
+}
 
-	// check if wordlist is a directory or a file
-	fi, err := os.Stat(g.Opts.Wordlist)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to stat wordlist: %v", err)
+// maybeRecurse asks the plugin whether r is worth brute forcing again and, if
+// so, queues a new job one depth deeper. Already-queued bases are skipped so
+// cycles and symlinked paths can't make the scan grow forever.
+func (g *Gobuster) maybeRecurse(r Result, depth int) {
+	if !g.Opts.Recursive || depth >= g.Opts.MaxDepth {
+		return
+	}
+	rp, ok := g.plugin.(RecursionAware)
+	if !ok {
+		return
+	}
+	baseURL, recurse := rp.ShouldRecurse(r)
+	if !recurse {
+		return
 	}
 
-	mode := fi.Mode()
+	g.visitedMu.Lock()
+	if g.visited[baseURL] {
+		g.visitedMu.Unlock()
+		return
+	}
+	g.visited[baseURL] = true
+	g.visitedMu.Unlock()
 
-	// is file
-	if mode.IsRegular() {
-		// Pull content from the wordlist
-		wordlist, err := os.Open(g.Opts.Wordlist)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open wordlist: %v", err)
-		}
+	job := recursionJob{BaseURL: baseURL, Depth: depth + 1}
+	g.queueMu.Lock()
+	g.pendingJobs = append(g.pendingJobs, job)
+	g.queueMu.Unlock()
 
-		lines, err := lineCounter(wordlist)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get number of lines: %v", err)
-		}
+	g.mu.Lock()
+	g.requestsQueued += len(g.words)
+	g.mu.Unlock()
+
+	g.queueWG.Add(1)
+	go g.runJob(job)
+}
 
-		g.requestsExpected = lines
-		g.requestsIssued = 0
+// loadWordlist reads Opts.Wordlist - a file, a directory of files (loaded in
+// file name order, user's responsibility to make sure they're all wordlists;
+// doesn't recurse into sub-dirs), or "-" for stdin - into memory exactly
+// once. runJob used to call this fresh for every recursionJob, re-opening
+// and re-counting the wordlist file(s) from disk on every discovered
+// directory; now every job just shares the words slice this populates.
+func (g *Gobuster) loadWordlist() error {
+	var words []string
 
-		// rewind wordlist
-		_, err = wordlist.Seek(0, 0)
+	if g.Opts.Wordlist == "-" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			words = append(words, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read wordlist from stdin: %v", err)
+		}
+	} else {
+		fi, err := os.Stat(g.Opts.Wordlist)
 		if err != nil {
-			return nil, fmt.Errorf("failed to rewind wordlist: %v", err)
+			return fmt.Errorf("Failed to stat wordlist: %v", err)
 		}
-		scanner := bufio.NewScanner(os.Stdin)
-		scannerarray := []bufio.Scanner{}
-		scannerarray = append(scannerarray, *scanner)
-		return &scannerarray, nil
-	}
 
-	// if we didn't return out the above block, we must be looking at a directory
+		var paths []string
+		if fi.Mode().IsRegular() {
+			paths = []string{g.Opts.Wordlist}
+		} else {
+			files, err := ioutil.ReadDir(g.Opts.Wordlist)
+			if err != nil {
+				return fmt.Errorf("failed to read wordlist directory: %v", err)
+			}
+			for _, f := range files {
+				paths = append(paths, g.Opts.Wordlist+"/"+f.Name())
+			}
+		}
 
-	// get all files in directory, just assume they are all wordlists (user's responsibility) -> doesn't do sub-dirs at this time, change to walkpath?
-	files, err := ioutil.ReadDir(g.Opts.Wordlist)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read wordlist directory: %v", err)
+		for _, p := range paths {
+			wordlist, err := os.Open(p)
+			if err != nil {
+				return fmt.Errorf("failed to open wordlist: %v", err)
+			}
+			scanner := bufio.NewScanner(wordlist)
+			for scanner.Scan() {
+				words = append(words, scanner.Text())
+			}
+			err = scanner.Err()
+			wordlist.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read wordlist: %v", err)
+			}
+		}
 	}
 
-	// start building our array of scanners
-	scannerarray := []bufio.Scanner{}
-	lines := 0
+	g.words = words
+	g.mu.Lock()
+	g.requestsExpected = len(words)
+	g.mu.Unlock()
+	return nil
+}
 
-	// pretty happy with how this turned out, seems to work fine - didn't realise it would be a thing!
-	for _, f := range files {
-		// Pull content from the wordlist
-		filewords, err := os.Open(g.Opts.Wordlist + "/" + f.Name())
-		if err != nil {
-			return nil, fmt.Errorf("failed to open wordlist: %v", err)
+// runJob feeds every word of the (already loaded, shared) wordlist into
+// jobChan tagged with job's baseURL/depth. Used both for the initial pass
+// and for every recursion step.
+//
+// job is only dropped from pendingJobs once every word it fed in has been
+// fully resolved by a worker (see recursionProgress/completeWord) - not as
+// soon as this function returns. jobChan is only buffered to Opts.Threads, so
+// "finished feeding" can otherwise mean up to Threads words are still sitting
+// unprocessed when a checkpoint is taken, with nothing recording that they're
+// still outstanding.
+func (g *Gobuster) runJob(job recursionJob) {
+	defer g.queueWG.Done()
+
+	g.queueMu.Lock()
+	g.jobProgress[job] = &recursionProgress{}
+	g.queueMu.Unlock()
+
+	// a --resume checkpoint only ever applies to the initial, non-recursive
+	// pass - recursion jobs are themselves recorded (and replayed) via the
+	// checkpoint's Queue, so they always start from the top of the wordlist
+	skip := 0
+	if job.Depth == 0 && job.BaseURL == "" {
+		skip = g.resumeOffset
+		if skip > len(g.words) {
+			skip = len(g.words)
 		}
+	}
 
-		// get current file line count
-		templines, err := lineCounter(filewords)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get number of lines: %v", err)
+	for _, word := range g.words[skip:] {
+		g.queueMu.Lock()
+		g.jobProgress[job].remaining++
+		g.queueMu.Unlock()
+		select {
+		case <-g.context.Done():
+			return
+		case g.jobChan <- wordJob{word: word, baseURL: job.BaseURL, depth: job.Depth}:
 		}
+	}
 
-		// add to total line count
-		lines += templines
-
-		// rewind current wordlist
-		_, err = filewords.Seek(0, 0)
-		if err != nil {
-			return nil, fmt.Errorf("failed to rewind wordlist: %v", err)
-		}
+	g.finishFeeding(job)
+}
 
-		filescanner := bufio.NewScanner(filewords)
-		scannerarray = append(scannerarray, *filescanner)
+// finishFeeding marks job as done feeding words into jobChan. If every word
+// it fed in has already been resolved, job is dropped from pendingJobs here;
+// otherwise completeWord drops it once the last one resolves.
+func (g *Gobuster) finishFeeding(job recursionJob) {
+	g.queueMu.Lock()
+	defer g.queueMu.Unlock()
+	st, ok := g.jobProgress[job]
+	if !ok {
+		return
+	}
+	st.feedingDone = true
+	if st.remaining == 0 {
+		g.dropPendingJobLocked(job)
 	}
+}
 
-	g.requestsExpected = lines
-	g.requestsIssued = 0
+// completeWord records that one word belonging to job - identified by the
+// baseURL/depth it ran at - has fully resolved, whether that's a genuine
+// success, an error, a skipped comment/blank line, or (after any number of
+// 429/503 retries) its final re-delivery. Once job has also finished feeding
+// the wordlist in and has no other words outstanding, it's dropped from
+// pendingJobs.
+func (g *Gobuster) completeWord(job recursionJob) {
+	g.queueMu.Lock()
+	defer g.queueMu.Unlock()
+	st, ok := g.jobProgress[job]
+	if !ok {
+		return
+	}
+	st.remaining--
+	if st.feedingDone && st.remaining <= 0 {
+		g.dropPendingJobLocked(job)
+	}
+}
 
-	return &scannerarray, nil
+// dropPendingJobLocked removes job from pendingJobs and jobProgress. Callers
+// must hold queueMu.
+func (g *Gobuster) dropPendingJobLocked(job recursionJob) {
+	delete(g.jobProgress, job)
+	for i, j := range g.pendingJobs {
+		if j == job {
+			g.pendingJobs = append(g.pendingJobs[:i], g.pendingJobs[i+1:]...)
+			break
+		}
+	}
 }
 
 // Start the busting of the website with the given
@@ -225,38 +485,91 @@ func (g *Gobuster) Start() error {
 		return err
 	}
 
+	checkpoint, err := g.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	if err := g.loadWordlist(); err != nil {
+		return err
+	}
+
 	var workerGroup sync.WaitGroup
 	workerGroup.Add(g.Opts.Threads)
 
-	wordChan := make(chan string, g.Opts.Threads)
+	g.jobChan = make(chan wordJob, g.Opts.Threads)
+	g.visited = make(map[string]bool)
+	g.jobProgress = make(map[recursionJob]*recursionProgress)
 
 	// Create goroutines for each of the number of threads
 	// specified.
 	for i := 0; i < g.Opts.Threads; i++ {
-		go g.worker(wordChan, &workerGroup)
+		go g.worker(g.jobChan, &workerGroup)
 	}
 
-	// This now return's multiple wordlists, but will only be 1 scanner if 1 wordlist -> multiple if wordlist directory chosen on CLI
-	scanners, err := g.getWordlist()
-	if err != nil {
-		return err
+	// The initial pass is just a recursion job at depth 0 against the plugin's
+	// own target (baseURL ""). Recursive hits queue more of these at depth+1 via
+	// maybeRecurse, so the plan keeps growing for as long as --recursive keeps
+	// finding new directories.
+	jobs := []recursionJob{{BaseURL: "", Depth: 0}}
+	if checkpoint != nil {
+		// a resumed run starts counting from where the checkpoint left off,
+		// and replays whatever recursion jobs hadn't finished yet
+		g.resumeOffset = checkpoint.WordlistOffset
+		g.depth0Issued = checkpoint.WordlistOffset
+		g.requestsIssued = checkpoint.RequestsIssued
+		jobs = append(jobs, checkpoint.Queue...)
 	}
 
-Scan:
-	// Is this derpy?! In theory it will work and complete 1 wordlist at a time in the order of the files?
-	// Update: doesn't seem as derpy as I thought. Seems to work fine during my test runs. Someone else confirm.
-	// Memory might be mental if like 10 MASSIVE lists are loaded though - but users be users.
-	for _, s := range *scanners {
-		for s.Scan() {
-			select {
-			case <-g.context.Done():
-				break Scan
-			case wordChan <- s.Text():
-			}
+	for _, job := range jobs {
+		if job.BaseURL != "" {
+			g.visited[job.BaseURL] = true
+			// a job resumed from a checkpoint's Queue is just as in-flight as
+			// one maybeRecurse queues mid-run - it needs to be in pendingJobs
+			// so a checkpoint taken before it finishes still records it
+			g.queueMu.Lock()
+			g.pendingJobs = append(g.pendingJobs, job)
+			g.queueMu.Unlock()
 		}
+		g.mu.Lock()
+		g.requestsQueued += len(g.words)
+		g.mu.Unlock()
+		g.queueWG.Add(1)
+		go g.runJob(job)
 	}
-	close(wordChan)
+
+	// once every in-flight and queued job has finished feeding the wordlist in,
+	// there's nothing left to produce - close jobChan so the workers drain out
+	go func() {
+		g.queueWG.Wait()
+		close(g.jobChan)
+	}()
+
+	stopCheckpoint := make(chan struct{})
+	go g.checkpointLoop(g.Opts.CheckpointInterval, stopCheckpoint)
+
 	workerGroup.Wait()
+	close(stopCheckpoint)
+
+	// the scan finished on its own (rather than being cut short by ctx
+	// cancellation) - there's nothing left to resume, so the checkpoint file
+	// would only be confusing if left behind
+	if g.Opts.Resume != "" && g.context.Err() == nil {
+		if err := os.Remove(g.Opts.Resume); err != nil && !os.IsNotExist(err) {
+			g.LogError.Printf("failed to remove completed resume file: %v", err)
+		}
+	} else if g.context.Err() != nil {
+		if err := g.saveCheckpoint(); err != nil {
+			g.LogError.Printf("failed to write final checkpoint: %v", err)
+		}
+	}
+
+	if f, ok := g.plugin.(Finisher); ok {
+		if err := f.Finish(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -264,3 +577,42 @@ Scan:
 func (g *Gobuster) GetConfigString() (string, error) {
 	return g.plugin.GetConfigString()
 }
+
+// RunWithSink runs the scan exactly like Start, except every Result and error
+// it produces is drained into sink instead of being left on Results()/Errors()
+// for the caller to pull off by hand. This is what -o/--format wires up to;
+// sink.Close() is always called once, even if the scan itself returns an
+// error.
+func (g *Gobuster) RunWithSink(sink ResultSink) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Start()
+	}()
+
+	resultChan := g.resultChan
+	errorChan := g.errorChan
+	for resultChan != nil || errorChan != nil {
+		select {
+		case r, ok := <-resultChan:
+			if !ok {
+				resultChan = nil
+				continue
+			}
+			if err := sink.Write(r); err != nil {
+				g.LogError.Println(err)
+			}
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
+			}
+			g.LogError.Println(err)
+		}
+	}
+
+	startErr := <-done
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	return startErr
+}
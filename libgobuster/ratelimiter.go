@@ -0,0 +1,108 @@
+package libgobuster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimiterFloorFactor bounds how far AIMD backoff can halve the rate:
+	// it never drops below ceiling/rateLimiterFloorFactor. A Retry-After pause
+	// is applied on every 429/503 whether or not the floor has been reached.
+	rateLimiterFloorFactor = 16
+	// rateLimiterRecoverAfter is how many consecutive non-429/503 results it
+	// takes to double the rate back up (the "additive increase" half of AIMD;
+	// doubling rather than +1 so recovery from a deep backoff isn't glacial).
+	rateLimiterRecoverAfter = 10
+)
+
+// RateLimiter is a shared, engine-wide token bucket with AIMD backoff: every
+// worker draws from the same bucket via Wait, and every 429/503 a worker sees
+// (via OnResult) halves the rate for everyone. rateLimiterRecoverAfter
+// consecutive non-429/503 results afterwards double it back up, capped at the
+// ceiling the user configured with --rate.
+type RateLimiter struct {
+	mu         sync.Mutex
+	limiter    *rate.Limiter
+	ceiling    rate.Limit
+	floor      rate.Limit
+	successRun int
+	pausedTill time.Time
+}
+
+// NewRateLimiter builds a RateLimiter capped at ratePerSecond, or returns nil
+// (a valid, always-allow no-op) if ratePerSecond is 0 - the default, since
+// --rate is opt-in.
+func NewRateLimiter(ratePerSecond float64) *RateLimiter {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+	ceiling := rate.Limit(ratePerSecond)
+	return &RateLimiter{
+		limiter: rate.NewLimiter(ceiling, 1),
+		ceiling: ceiling,
+		floor:   ceiling / rateLimiterFloorFactor,
+	}
+}
+
+// Wait blocks until a token is available, ctx is done, or the limiter is
+// paused for a Retry-After window reported by OnResult - whichever is later.
+// A nil receiver (no --rate given) never blocks.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	pausedTill := r.pausedTill
+	r.mu.Unlock()
+
+	if until := time.Until(pausedTill); until > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(until):
+		}
+	}
+
+	return r.limiter.Wait(ctx)
+}
+
+// OnResult feeds a single result's status back into the AIMD controller. It's
+// HTTP-semantics-aware (429/503 trigger backoff) so plugins don't each have to
+// reimplement the same policy, but it only needs the status code gobuster
+// already tracks on every Result - no plugin-specific wiring required.
+func (r *RateLimiter) OnResult(res Result, retryAfter time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if res.StatusCode == 429 || res.StatusCode == 503 {
+		r.successRun = 0
+		newLimit := r.limiter.Limit() / 2
+		if newLimit < r.floor {
+			newLimit = r.floor
+		}
+		r.limiter.SetLimit(newLimit)
+		if retryAfter > 0 {
+			r.pausedTill = time.Now().Add(retryAfter)
+		}
+		return
+	}
+
+	r.successRun++
+	if r.successRun >= rateLimiterRecoverAfter {
+		r.successRun = 0
+		newLimit := r.limiter.Limit() * 2
+		if newLimit > r.ceiling {
+			newLimit = r.ceiling
+		}
+		r.limiter.SetLimit(newLimit)
+	}
+}
@@ -0,0 +1,74 @@
+package libgobuster
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter_Disabled(t *testing.T) {
+	r := NewRateLimiter(0)
+	if r != nil {
+		t.Fatalf("NewRateLimiter(0) = %v, want nil (no-op)", r)
+	}
+	// a nil *RateLimiter must be safe to call through, exactly like --rate
+	// not being given at all
+	if err := r.Wait(context.Background()); err != nil {
+		t.Errorf("nil RateLimiter.Wait() = %v, want nil", err)
+	}
+	r.OnResult(Result{StatusCode: 429}, 0)
+}
+
+func TestRateLimiter_OnResult_BacksOffOn429(t *testing.T) {
+	r := NewRateLimiter(100)
+	before := r.limiter.Limit()
+
+	r.OnResult(Result{StatusCode: 429}, 0)
+
+	after := r.limiter.Limit()
+	if after >= before {
+		t.Errorf("limit after 429 = %v, want less than %v", after, before)
+	}
+}
+
+func TestRateLimiter_OnResult_FloorsBackoff(t *testing.T) {
+	r := NewRateLimiter(100)
+	for i := 0; i < 20; i++ {
+		r.OnResult(Result{StatusCode: 503}, 0)
+	}
+	if r.limiter.Limit() < r.floor {
+		t.Errorf("limit = %v, fell below floor %v", r.limiter.Limit(), r.floor)
+	}
+}
+
+func TestRateLimiter_OnResult_RecoversAfterSuccesses(t *testing.T) {
+	r := NewRateLimiter(100)
+	r.OnResult(Result{StatusCode: 429}, 0)
+	halved := r.limiter.Limit()
+
+	for i := 0; i < rateLimiterRecoverAfter-1; i++ {
+		r.OnResult(Result{StatusCode: 200}, 0)
+	}
+	if r.limiter.Limit() != halved {
+		t.Fatalf("limit recovered before rateLimiterRecoverAfter successes: got %v, want unchanged %v", r.limiter.Limit(), halved)
+	}
+
+	r.OnResult(Result{StatusCode: 200}, 0)
+	if r.limiter.Limit() <= halved {
+		t.Errorf("limit after %d successes = %v, want greater than %v", rateLimiterRecoverAfter, r.limiter.Limit(), halved)
+	}
+}
+
+func TestRateLimiter_OnResult_RetryAfterPausesWait(t *testing.T) {
+	r := NewRateLimiter(100)
+	r.OnResult(Result{StatusCode: 429}, 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := r.Wait(ctx)
+	if err == nil {
+		t.Fatalf("Wait() with a 50ms pause and a 10ms ctx timeout should have returned ctx.Err(), got nil after %v", time.Since(start))
+	}
+}
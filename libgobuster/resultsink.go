@@ -0,0 +1,241 @@
+package libgobuster
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ResultSink is implemented by anything that can consume a scan's Results as
+// they're produced. Gobuster.RunWithSink writes every result to whichever sink
+// -o/--format picked instead of the caller having to format ResultToString
+// output by hand.
+type ResultSink interface {
+	// Write is called once per Result, in the order they're produced.
+	Write(r Result) error
+	// Close flushes any buffered header/footer content (SARIF and CSV need
+	// one, NDJSON and text don't) and is always called exactly once, even if
+	// the scan errored out.
+	Close() error
+}
+
+// sinkResult is the common, format-agnostic shape every structured sink
+// serializes. It mirrors the richer fields gobusterdir now populates on
+// Result (ContentType, Location, word/line counts) so jq/Burp/DefectDojo get
+// something more useful than a pre-formatted string.
+type sinkResult struct {
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status"`
+	Size        int64  `json:"size"`
+	Length      int64  `json:"length"`
+	Redirect    string `json:"redirect,omitempty"`
+	Words       int    `json:"words"`
+	Lines       int    `json:"lines"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+func toSinkResult(r Result) sinkResult {
+	var size int64
+	if r.Size != nil {
+		size = *r.Size
+	}
+	return sinkResult{
+		URL:         r.URL,
+		StatusCode:  r.StatusCode,
+		Size:        size,
+		Length:      size,
+		Redirect:    r.Location,
+		Words:       r.Words,
+		Lines:       r.Lines,
+		ContentType: r.ContentType,
+	}
+}
+
+// NewResultSink builds the ResultSink for the requested --format, writing to
+// w. gobuster is only needed by the "text" sink, which defers to the plugin's
+// own ResultToString to keep today's output byte-for-byte unchanged.
+func NewResultSink(format string, w io.Writer, gobuster *Gobuster) (ResultSink, error) {
+	switch format {
+	case "", "text":
+		return &textResultSink{gobuster: gobuster, w: w}, nil
+	case "jsonl", "json", "ndjson":
+		return &ndjsonResultSink{w: w, enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvResultSink{w: csv.NewWriter(w)}, nil
+	case "sarif":
+		return &sarifResultSink{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// textResultSink is the sink used when no --format is given: exactly gobuster's
+// historic behaviour of rendering each Result through the plugin's own
+// ResultToString.
+type textResultSink struct {
+	gobuster *Gobuster
+	w        io.Writer
+}
+
+func (s *textResultSink) Write(r Result) error {
+	line, err := s.gobuster.plugin.ResultToString(&r)
+	if err != nil {
+		return err
+	}
+	if line == nil {
+		return nil
+	}
+	_, err = fmt.Fprint(s.w, *line)
+	return err
+}
+
+func (s *textResultSink) Close() error {
+	return nil
+}
+
+// ndjsonResultSink writes one JSON object per line, ready to pipe into jq.
+type ndjsonResultSink struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func (s *ndjsonResultSink) Write(r Result) error {
+	return s.enc.Encode(toSinkResult(r))
+}
+
+func (s *ndjsonResultSink) Close() error {
+	return nil
+}
+
+var csvHeader = []string{"url", "status", "size", "length", "redirect", "words", "lines", "content_type"}
+
+// csvResultSink writes the same fields as the JSON sinks, header first.
+type csvResultSink struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (s *csvResultSink) Write(r Result) error {
+	if !s.wroteHeader {
+		if err := s.w.Write(csvHeader); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+
+	sr := toSinkResult(r)
+	row := []string{
+		sr.URL,
+		strconv.Itoa(sr.StatusCode),
+		strconv.FormatInt(sr.Size, 10),
+		strconv.FormatInt(sr.Length, 10),
+		sr.Redirect,
+		strconv.Itoa(sr.Words),
+		strconv.Itoa(sr.Lines),
+		sr.ContentType,
+	}
+	return s.w.Write(row)
+}
+
+func (s *csvResultSink) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// sarifRun/sarifResult etc are a deliberately small subset of the SARIF 2.1.0
+// schema - just enough for GitHub code scanning and similar CI consumers to
+// import a gobuster run as a set of "rule" matches, one rule per status code.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifResultSink buffers every Result, since a SARIF log is a single JSON
+// document rather than a stream, and emits it on Close.
+type sarifResultSink struct {
+	w       io.Writer
+	results []Result
+}
+
+func (s *sarifResultSink) Write(r Result) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func (s *sarifResultSink) Close() error {
+	rules := map[string]bool{}
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "gobuster"}}}
+	for _, r := range s.results {
+		ruleID := strconv.Itoa(r.StatusCode)
+		if !rules[ruleID] {
+			rules[ruleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: ruleID})
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID: ruleID,
+			Level:  "note",
+			Message: sarifMessage{
+				Text: fmt.Sprintf("%s returned status %d", r.URL, r.StatusCode),
+			},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: r.URL},
+				},
+			}},
+		})
+	}
+
+	doc := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	enc := json.NewEncoder(s.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,105 @@
+package libgobuster
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToSinkResult(t *testing.T) {
+	size := int64(1234)
+	r := Result{
+		URL:         "http://example.com/admin/",
+		StatusCode:  301,
+		Size:        &size,
+		Location:    "http://example.com/admin/login",
+		Words:       12,
+		Lines:       3,
+		ContentType: "text/html",
+	}
+
+	sr := toSinkResult(r)
+	if sr.URL != r.URL || sr.StatusCode != r.StatusCode || sr.Size != size || sr.Length != size ||
+		sr.Redirect != r.Location || sr.Words != r.Words || sr.Lines != r.Lines || sr.ContentType != r.ContentType {
+		t.Errorf("toSinkResult(%+v) = %+v, fields didn't carry over as expected", r, sr)
+	}
+}
+
+func TestToSinkResult_NilSize(t *testing.T) {
+	sr := toSinkResult(Result{URL: "http://example.com/"})
+	if sr.Size != 0 || sr.Length != 0 {
+		t.Errorf("toSinkResult with nil Size = %+v, want Size/Length 0", sr)
+	}
+}
+
+func TestCSVResultSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &csvResultSink{w: csv.NewWriter(&buf)}
+
+	size := int64(42)
+	if err := sink.Write(Result{URL: "http://example.com/a", StatusCode: 200, Size: &size}); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse csv output: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2 (header + one result): %v", len(rows), rows)
+	}
+	if !equalStrings(rows[0], csvHeader) {
+		t.Errorf("header row = %v, want %v", rows[0], csvHeader)
+	}
+	if rows[1][0] != "http://example.com/a" || rows[1][1] != "200" || rows[1][2] != "42" {
+		t.Errorf("data row = %v, want url/status/size of http://example.com/a, 200, 42", rows[1])
+	}
+}
+
+func TestSarifResultSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &sarifResultSink{w: &buf}
+
+	if err := sink.Write(Result{URL: "http://example.com/admin/", StatusCode: 200}); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := sink.Write(Result{URL: "http://example.com/secret/", StatusCode: 200}); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	var doc sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Close() produced invalid JSON: %v", err)
+	}
+	if len(doc.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(doc.Runs))
+	}
+	run := doc.Runs[0]
+	if len(run.Results) != 2 {
+		t.Errorf("got %d results, want 2", len(run.Results))
+	}
+	// both results share status 200, so they collapse into a single rule
+	if len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "200" {
+		t.Errorf("rules = %v, want a single rule with ID \"200\"", run.Tool.Driver.Rules)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}